@@ -0,0 +1,286 @@
+package raygun
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// errRejectedByBulkEndpoint is the synthetic error passed to RetryPolicy and
+// DeadLetterHandler for an item the bulk endpoint accepted the request for
+// (2xx) but listed as failed in the per-item failure list.
+var errRejectedByBulkEndpoint = errors.New("raygun: item rejected by bulk endpoint")
+
+// RaygunBulkEndpoint is the path appended to RaygunEndpoint when Batching is
+// enabled.
+var RaygunBulkEndpoint = "/entries/bulk"
+
+// Batching accumulates Rays into batches, flushing as soon as a batch
+// reaches maxItems Rays or maxBytes of marshalled JSON (the Ray that
+// crosses maxBytes is included, so a batch can exceed it) and at least
+// every flushInterval, then POSTs the batch as a single JSON array to
+// RaygunBulkEndpoint instead of issuing one request per Ray. It is off by
+// default, preserving the existing single-post behaviour.
+func Batching(maxItems int, maxBytes int, flushInterval time.Duration) RaygunCollectorConfig {
+	return func(c *RaygunCollector) {
+		c.BatchingEnabled = true
+		c.BatchMaxItems = maxItems
+		c.BatchMaxBytes = maxBytes
+		c.BatchFlushInterval = flushInterval
+	}
+}
+
+// bulkResponse describes a partial-failure response from the bulk endpoint:
+// the zero-based indexes, within the posted batch, of Rays that were not
+// accepted. An empty or absent Failed list means the whole batch succeeded.
+type bulkResponse struct {
+	Failed []int `json:"failed"`
+}
+
+// startBatching runs the accumulate-and-flush loop used when Batching is
+// enabled, in place of the per-Ray workers started by start().
+func (c *RaygunCollector) startBatching() {
+	go func() {
+		batch := make([]queuedItem, 0, c.BatchMaxItems)
+		batchBytes := 0
+
+		ticker := time.NewTicker(c.BatchFlushInterval)
+		defer ticker.Stop()
+
+		flush := func() {
+			if len(batch) == 0 {
+				return
+			}
+			c.sendBatch(batch)
+			batch = make([]queuedItem, 0, c.BatchMaxItems)
+			batchBytes = 0
+		}
+
+		for {
+			select {
+			case item := <-c.queue:
+				encoded, err := json.Marshal(item.ray)
+				if err != nil {
+					c.Logger.Printf("raygun: failed to marshal raygun error: %s", err.Error())
+					c.DeadLetterHandler(item.ray, err)
+					c.ack(item)
+					c.wg.Done()
+					continue
+				}
+
+				batch = append(batch, item)
+				batchBytes += len(encoded)
+
+				if len(batch) >= c.BatchMaxItems || (c.BatchMaxBytes > 0 && batchBytes >= c.BatchMaxBytes) {
+					flush()
+				}
+			case <-ticker.C:
+				flush()
+			case <-c.done:
+				flush()
+				return
+			}
+		}
+	}()
+}
+
+// sendBatch POSTs batch to the bulk endpoint, retrying on transient
+// failures - whether the whole batch failing at the transport/HTTP level,
+// or individual items coming back in a 2xx response's per-item failure
+// list - according to c.RetryPolicy, up to a single shared c.MaxAttempts
+// budget per item. A batch that is never accepted is handed item-by-item
+// to c.DeadLetterHandler.
+//
+// Every item in batch is always attempted together and shares the same
+// bulkAttempts count: batch[0].bulkAttempts tells us how many rounds this
+// group has already been through (0 for a fresh batch, >0 for a retry
+// handed back by handleBulkResponse), and the loop below continues
+// counting from there instead of resetting to 1, so a retried sub-batch
+// can never exceed the budget its items already started with.
+func (c *RaygunCollector) sendBatch(batch []queuedItem) {
+	rays := make([]Ray, len(batch))
+	for i, item := range batch {
+		rays[i] = item.ray
+	}
+
+	body, err := json.Marshal(rays)
+	if err != nil {
+		c.Logger.Printf("raygun: failed to marshal ray batch: %s", err.Error())
+		c.deadLetterBatch(batch, err)
+		return
+	}
+
+	started := 0
+	if len(batch) > 0 {
+		started = batch[0].bulkAttempts
+	}
+
+	var lastErr error
+
+	for attempt := started + 1; attempt <= c.MaxAttempts; attempt++ {
+		for i := range batch {
+			batch[i].bulkAttempts = attempt
+		}
+
+		res, respBody, err := c.postBatch(body)
+		if err == nil && res != nil && res.StatusCode >= 200 && res.StatusCode < 300 {
+			c.handleBulkResponse(batch, respBody)
+			return
+		}
+
+		lastErr = err
+		if err == nil {
+			lastErr = errorForStatus(res.StatusCode)
+		}
+
+		if attempt == c.MaxAttempts {
+			break
+		}
+
+		delay, retry := c.RetryPolicy(attempt, res, err)
+		if !retry {
+			break
+		}
+		if override, ok := retryAfterDelay(res); ok {
+			delay = override
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-c.done:
+			timer.Stop()
+			c.deadLetterBatch(batch, lastErr)
+			return
+		}
+	}
+
+	c.deadLetterBatch(batch, lastErr)
+}
+
+// postBatch performs a single bulk POST attempt and returns the response
+// along with its fully-read body, since the body must be inspected for
+// per-item failures after the connection is released. A body read failure
+// is returned as an error rather than folded into a nil, since the caller
+// treats err == nil as "the response is trustworthy" - a 2xx status alone
+// doesn't mean the (unreadable) per-item failure list was empty.
+func (c *RaygunCollector) postBatch(body []byte) (*http.Response, []byte, error) {
+	req, err := http.NewRequest("POST", RaygunEndpoint+RaygunBulkEndpoint, bytes.NewBuffer(body))
+	if err != nil {
+		c.Logger.Printf("raygun: failed to create bulk request: %s", err.Error())
+		return nil, nil, err
+	}
+	req.Header.Add("X-ApiKey", c.ApiKey)
+	req.Header.Add("Content-Type", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		c.Logger.Printf("raygun: bulk request failed: %s", err.Error())
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	respBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		c.Logger.Printf("raygun: failed to read bulk response: %s", err.Error())
+		return res, nil, err
+	}
+
+	return res, respBody, nil
+}
+
+// handleBulkResponse reconciles a successful bulk POST against its
+// per-item failure list: accepted Rays are acked and marked done. Failed
+// items are retried as a fresh (smaller) batch, backed off like any other
+// retry and subject to the same shared c.MaxAttempts budget tracked on
+// bulkAttempts (already bumped by sendBatch for this attempt before it
+// called us). Items that exhaust their budget are handed to
+// c.DeadLetterHandler, never recursed on forever.
+//
+// If the response body can't be parsed, we have no idea which items (if
+// any) the server actually accepted; rather than let the zero-valued,
+// empty Failed list be misread as "everything succeeded", every item in
+// batch is treated as failed and routed through the same retry/give-up
+// path.
+func (c *RaygunCollector) handleBulkResponse(batch []queuedItem, body []byte) {
+	var resp bulkResponse
+	parsed := true
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &resp); err != nil {
+			c.Logger.Printf("raygun: failed to parse bulk response: %s", err.Error())
+			parsed = false
+		}
+	}
+
+	if parsed && len(resp.Failed) == 0 {
+		for _, item := range batch {
+			c.ack(item)
+		}
+		c.wg.Add(-len(batch))
+		return
+	}
+
+	failed := make(map[int]bool, len(batch))
+	if parsed {
+		for _, idx := range resp.Failed {
+			failed[idx] = true
+		}
+	} else {
+		for i := range batch {
+			failed[i] = true
+		}
+	}
+
+	retry := make([]queuedItem, 0, len(batch))
+	giveUp := make([]queuedItem, 0)
+	for i, item := range batch {
+		if !failed[i] {
+			c.ack(item)
+			c.wg.Done()
+			continue
+		}
+
+		if item.bulkAttempts >= c.MaxAttempts {
+			giveUp = append(giveUp, item)
+			continue
+		}
+		retry = append(retry, item)
+	}
+
+	if len(giveUp) > 0 {
+		c.deadLetterBatch(giveUp, errRejectedByBulkEndpoint)
+	}
+	if len(retry) == 0 {
+		return
+	}
+
+	delay, ok := c.RetryPolicy(retry[0].bulkAttempts, nil, errRejectedByBulkEndpoint)
+	if !ok {
+		c.deadLetterBatch(retry, errRejectedByBulkEndpoint)
+		return
+	}
+
+	go func() {
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			c.sendBatch(retry)
+		case <-c.done:
+			timer.Stop()
+			c.deadLetterBatch(retry, errRejectedByBulkEndpoint)
+		}
+	}()
+}
+
+// deadLetterBatch hands every Ray in batch to c.DeadLetterHandler, acks its
+// spool record if any, and marks it as no longer outstanding.
+func (c *RaygunCollector) deadLetterBatch(batch []queuedItem, err error) {
+	for _, item := range batch {
+		c.DeadLetterHandler(item.ray, err)
+		c.ack(item)
+		c.wg.Done()
+	}
+}
@@ -0,0 +1,136 @@
+package raygun
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestBatchCollector(t *testing.T, maxAttempts int, policy RetryPolicy, onDeadLetter func(Ray, error)) *RaygunCollector {
+	t.Helper()
+	return NewCollector("app", "key", WithoutSend(), MaxAttempts(maxAttempts),
+		WithRetryPolicy(policy),
+		WithDeadLetterHandler(onDeadLetter),
+	).(*RaygunCollector)
+}
+
+func waitForWaitGroup(t *testing.T, c *RaygunCollector) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		c.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("collector's wg never reached zero")
+	}
+}
+
+func TestHandleBulkResponseAcksOnFullSuccess(t *testing.T) {
+	var mu sync.Mutex
+	var deadLettered int
+
+	c := newTestBatchCollector(t, 3, DefaultRetryPolicy, func(Ray, error) {
+		mu.Lock()
+		deadLettered++
+		mu.Unlock()
+	})
+
+	batch := []queuedItem{{ray: NewRay("a")}, {ray: NewRay("b")}}
+	c.wg.Add(len(batch))
+
+	c.handleBulkResponse(batch, []byte(`{"failed":[]}`))
+	waitForWaitGroup(t, c)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if deadLettered != 0 {
+		t.Fatalf("expected no dead-lettered items on full success, got %d", deadLettered)
+	}
+}
+
+func TestHandleBulkResponseGivesUpOnceAttemptsExhausted(t *testing.T) {
+	var mu sync.Mutex
+	var deadLettered int
+
+	c := newTestBatchCollector(t, 3, DefaultRetryPolicy, func(Ray, error) {
+		mu.Lock()
+		deadLettered++
+		mu.Unlock()
+	})
+
+	// batch[1] already used up every attempt, so it must be given up on
+	// directly rather than scheduled for another retry.
+	batch := []queuedItem{
+		{ray: NewRay("ok"), bulkAttempts: 1},
+		{ray: NewRay("exhausted"), bulkAttempts: 3},
+	}
+	c.wg.Add(len(batch))
+
+	c.handleBulkResponse(batch, []byte(`{"failed":[1]}`))
+	waitForWaitGroup(t, c)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if deadLettered != 1 {
+		t.Fatalf("expected exactly the exhausted item to be dead-lettered, got %d", deadLettered)
+	}
+}
+
+func TestHandleBulkResponseTreatsUnparsableBodyAsAllFailed(t *testing.T) {
+	var mu sync.Mutex
+	var deadLettered int
+
+	// A RetryPolicy that declines makes the outcome deterministic and
+	// synchronous: every item must be dead-lettered immediately rather
+	// than the empty, zero-valued Failed list being misread as success.
+	c := newTestBatchCollector(t, 3, func(int, *http.Response, error) (time.Duration, bool) {
+		return 0, false
+	}, func(Ray, error) {
+		mu.Lock()
+		deadLettered++
+		mu.Unlock()
+	})
+
+	batch := []queuedItem{{ray: NewRay("a")}, {ray: NewRay("b")}}
+	c.wg.Add(len(batch))
+
+	c.handleBulkResponse(batch, []byte("not json"))
+	waitForWaitGroup(t, c)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if deadLettered != len(batch) {
+		t.Fatalf("expected every item dead-lettered when the response body can't be parsed, got %d", deadLettered)
+	}
+}
+
+func TestHandleBulkResponseDeadLettersRetryScheduledOnClose(t *testing.T) {
+	var mu sync.Mutex
+	var deadLettered int
+
+	c := newTestBatchCollector(t, 3, func(int, *http.Response, error) (time.Duration, bool) {
+		return 10 * time.Second, true
+	}, func(Ray, error) {
+		mu.Lock()
+		deadLettered++
+		mu.Unlock()
+	})
+
+	batch := []queuedItem{{ray: NewRay("a"), bulkAttempts: 1}}
+	c.wg.Add(len(batch))
+
+	c.handleBulkResponse(batch, []byte(`{"failed":[0]}`))
+	close(c.done)
+
+	waitForWaitGroup(t, c)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if deadLettered != 1 {
+		t.Fatalf("expected the still-scheduled retry to be dead-lettered once the collector closed, got %d", deadLettered)
+	}
+}
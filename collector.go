@@ -2,11 +2,13 @@ package raygun
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -24,9 +26,71 @@ type RaygunCollector struct {
 	QueueSize int
 	Logger    *log.Logger
 
-	queue  chan Ray
-	client *http.Client
-	wg     sync.WaitGroup
+	MaxAttempts       int
+	RetryPolicy       RetryPolicy
+	DeadLetterHandler DeadLetterHandler
+
+	BatchingEnabled    bool
+	BatchMaxItems      int
+	BatchMaxBytes      int
+	BatchFlushInterval time.Duration
+
+	Middlewares []Middleware
+
+	BlockOnFull     bool
+	OverflowHandler OverflowHandler
+
+	SpoolDir      string
+	SpoolMaxBytes int64
+
+	Repository      RayRepository
+	WithoutSendMode bool
+
+	queue           chan queuedItem
+	client          *http.Client
+	wg              sync.WaitGroup
+	done            chan struct{}
+	handler         Handler
+	closed          int32
+	draining        int32
+	spool           *spool
+	backlog         *spoolBacklog
+	replayerStopped chan struct{}
+}
+
+// queuedItem is the internal unit of work passed through c.queue. spooled
+// is set when ray was durably recorded to the spool before being queued,
+// in which case spoolID must be acked once delivery is finally resolved.
+// bulkAttempts counts the bulk POST attempts this item has been part of,
+// the first one included (a retry of the failed subset of a batch runs
+// sendBatch again in a fresh goroutine, so that count has to live on the item itself
+// rather than a loop variable); it is unused by the single-item deliver
+// path, which tracks attempts with a local loop variable instead.
+type queuedItem struct {
+	ray          Ray
+	spoolID      spoolRecordID
+	spooled      bool
+	bulkAttempts int
+}
+
+// ack marks item as durably resolved (delivered or dead-lettered), freeing
+// its spool record, if any, for compaction.
+func (c *RaygunCollector) ack(item queuedItem) {
+	if !item.spooled {
+		return
+	}
+	if err := c.spool.ack(item.spoolID); err != nil {
+		c.Logger.Printf("raygun: failed to ack spooled ray: %s", err.Error())
+	}
+}
+
+// deadLetterAbandoned resolves item as given up on for reason err: it is
+// handed off to c.DeadLetterHandler, acked, and marked as no longer
+// outstanding. Used for items Close decides nobody will ever deliver.
+func (c *RaygunCollector) deadLetterAbandoned(item queuedItem, err error) {
+	c.DeadLetterHandler(item.ray, err)
+	c.ack(item)
+	c.wg.Done()
 }
 
 type RaygunCollectorConfig func(*RaygunCollector)
@@ -52,10 +116,12 @@ func Logger(logger *log.Logger) RaygunCollectorConfig {
 func NewCollector(appName, apiKey string, options ...RaygunCollectorConfig) Collector {
 
 	collector := &RaygunCollector{
-		AppName:   appName,
-		ApiKey:    apiKey,
-		Workers:   1,
-		QueueSize: 10000,
+		AppName:     appName,
+		ApiKey:      apiKey,
+		Workers:     1,
+		QueueSize:   10000,
+		MaxAttempts: 5,
+		RetryPolicy: DefaultRetryPolicy,
 		client: &http.Client{
 			Transport: &http.Transport{
 				MaxIdleConns:       10,
@@ -74,11 +140,77 @@ func NewCollector(appName, apiKey string, options ...RaygunCollectorConfig) Coll
 		collector.Logger = log.New(ioutil.Discard, "raygun", 0)
 	}
 
-	collector.queue = make(chan Ray, collector.QueueSize)
+	if collector.DeadLetterHandler == nil {
+		collector.DeadLetterHandler = defaultDeadLetterHandler(collector.Logger)
+	}
+
+	if collector.OverflowHandler == nil {
+		collector.OverflowHandler = defaultOverflowHandler(collector.Logger)
+	}
+
+	collector.queue = make(chan queuedItem, collector.QueueSize)
+	collector.done = make(chan struct{})
+
+	if collector.SpoolDir != "" && !collector.WithoutSendMode {
+		sp, pending, err := openSpool(collector.SpoolDir, collector.SpoolMaxBytes)
+		if err != nil {
+			collector.Logger.Printf("raygun: failed to open spool: %s", err.Error())
+		} else {
+			collector.spool = sp
+			collector.backlog = newSpoolBacklog()
+			collector.replayerStopped = make(chan struct{})
+			for _, rec := range pending {
+				collector.wg.Add(1)
+				collector.backlog.push(queuedItem{ray: rec.ray, spoolID: rec.id, spooled: true})
+			}
+			collector.startReplayer()
+		}
+	}
+
+	collector.handler = collector.buildHandler()
 	collector.start()
 	return collector
 }
 
+// startReplayer feeds items held in the spool backlog - either left over
+// from a previous run or parked there because the queue was full - into
+// the queue as capacity frees up.
+//
+// Once Close starts draining (c.draining set), the replayer stops touching
+// c.queue entirely rather than racing a send against c.done: with c.queue
+// buffered, "send" and "<-c.done" can both be ready at once, and select
+// picks between ready cases at random, so a plain send-vs-done race could
+// still push an item into the queue after every worker - and Close's own
+// drain pass - has already given up on reading from it. Close waits on
+// replayerStopped before doing its final drain, so by then this goroutine
+// is guaranteed to have made its last decision about every item it held.
+func (c *RaygunCollector) startReplayer() {
+	go func() {
+		defer close(c.replayerStopped)
+		for {
+			item, ok := c.backlog.pop()
+			if !ok {
+				return
+			}
+
+			if atomic.LoadInt32(&c.draining) == 1 {
+				c.deadLetterAbandoned(item, ErrCollectorClosed)
+				continue
+			}
+
+			select {
+			case c.queue <- item:
+			case <-c.done:
+				// Close is shutting down and no worker will ever read this
+				// item out of c.queue; resolve it here instead of dropping
+				// it silently.
+				c.deadLetterAbandoned(item, ErrCollectorClosed)
+				return
+			}
+		}
+	}()
+}
+
 type CaptureOption func(*Ray)
 
 func WithUser(id string) CaptureOption {
@@ -104,8 +236,9 @@ func (c *RaygunCollector) CaptureMessage(msg string, opts ...CaptureOption) {
 	for _, f := range opts {
 		f(&ray)
 	}
-	c.queue <- ray
-	c.wg.Add(1)
+	if err := c.handler(context.Background(), &ray); err != nil {
+		c.Logger.Printf("raygun: failed to capture ray: %s", err.Error())
+	}
 }
 
 func (c *RaygunCollector) CaptureError(err error, opts ...CaptureOption) {
@@ -123,39 +256,108 @@ func (c *RaygunCollector) CapturePanic() {
 }
 
 func (c *RaygunCollector) Capture(ray Ray) {
-	c.queue <- ray
-	c.wg.Add(1)
+	if err := c.handler(context.Background(), &ray); err != nil {
+		c.Logger.Printf("raygun: failed to capture ray: %s", err.Error())
+	}
 }
 
 func (c *RaygunCollector) start() {
+	if c.WithoutSendMode {
+		return
+	}
+
+	if c.BatchingEnabled {
+		c.startBatching()
+		return
+	}
+
 	for i := 0; i < c.Workers; i++ {
 		go func() {
 			for {
-				ray := <-c.queue
-
-				json, err := json.Marshal(ray)
-				if err != nil {
-					c.Logger.Printf("raygun: failed to marshal raygun error: %s", err.Error())
+				select {
+				case item := <-c.queue:
+					c.deliver(item)
+					c.wg.Done()
+				case <-c.done:
+					return
 				}
+			}
+		}()
+	}
+}
 
-				req, err := http.NewRequest("POST", RaygunEndpoint+"/entries", bytes.NewBuffer(json))
-				if err != nil {
-					c.Logger.Printf("raygun: failed to create error request: %s", err.Error())
-				}
-				req.Header.Add("X-ApiKey", c.ApiKey)
-				res, err := c.client.Do(req)
-				if err != nil {
-					c.Logger.Printf("raygun: request failed: %s", err.Error())
-				}
+// deliver posts item to Raygun, retrying on transient failures according to
+// c.RetryPolicy until delivery succeeds, the policy gives up, or
+// c.MaxAttempts is reached. A Ray that is never delivered is handed to
+// c.DeadLetterHandler. Either way, item's spool record (if any) is acked
+// once its fate is resolved.
+func (c *RaygunCollector) deliver(item queuedItem) {
+	ray := item.ray
+	var lastErr error
+
+	for attempt := 1; attempt <= c.MaxAttempts; attempt++ {
+		res, err := c.send(ray)
+		if err == nil && res != nil && res.StatusCode >= 200 && res.StatusCode < 300 {
+			c.ack(item)
+			return
+		}
 
-				if res != nil {
-					res.Body.Close()
-				}
+		lastErr = err
+		if err == nil {
+			lastErr = errorForStatus(res.StatusCode)
+		}
 
-				c.wg.Done()
-			}
-		}()
+		if attempt == c.MaxAttempts {
+			break
+		}
+
+		delay, retry := c.RetryPolicy(attempt, res, err)
+		if !retry {
+			break
+		}
+		if override, ok := retryAfterDelay(res); ok {
+			delay = override
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-c.done:
+			timer.Stop()
+			c.DeadLetterHandler(ray, lastErr)
+			c.ack(item)
+			return
+		}
+	}
+
+	c.DeadLetterHandler(ray, lastErr)
+	c.ack(item)
+}
+
+// send performs a single POST attempt for ray and returns the response, if
+// any, and any error encountered creating or issuing the request.
+func (c *RaygunCollector) send(ray Ray) (*http.Response, error) {
+	body, err := json.Marshal(ray)
+	if err != nil {
+		c.Logger.Printf("raygun: failed to marshal raygun error: %s", err.Error())
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", RaygunEndpoint+"/entries", bytes.NewBuffer(body))
+	if err != nil {
+		c.Logger.Printf("raygun: failed to create error request: %s", err.Error())
+		return nil, err
+	}
+	req.Header.Add("X-ApiKey", c.ApiKey)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		c.Logger.Printf("raygun: request failed: %s", err.Error())
+		return nil, err
 	}
+	defer res.Body.Close()
+
+	return res, nil
 }
 
 func (c *RaygunCollector) Wait() {
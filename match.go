@@ -0,0 +1,61 @@
+package raygun
+
+import "context"
+
+// Action describes what Match should do with a Ray that satisfies its
+// predicate. Build one with Drop, Tag, or Route.
+type Action struct {
+	kind  actionKind
+	tag   string
+	route Collector
+}
+
+type actionKind int
+
+const (
+	actionDrop actionKind = iota
+	actionTag
+	actionRoute
+)
+
+// Drop discards a matched Ray; it never reaches the rest of the pipeline.
+func Drop() Action {
+	return Action{kind: actionDrop}
+}
+
+// Tag appends tag to a matched Ray's tags and lets it continue down the
+// pipeline.
+func Tag(tag string) Action {
+	return Action{kind: actionTag, tag: tag}
+}
+
+// Route hands a matched Ray to an alternate Collector instead of this
+// one's own pipeline.
+func Route(to Collector) Action {
+	return Action{kind: actionRoute, route: to}
+}
+
+// Match returns a Middleware that applies action to any Ray for which
+// pred reports true, letting everything else pass through unchanged.
+func Match(pred func(*Ray) bool, action Action) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, ray *Ray) error {
+			if !pred(ray) {
+				return next(ctx, ray)
+			}
+
+			switch action.kind {
+			case actionDrop:
+				return nil
+			case actionTag:
+				ray.Details.Tags = append(ray.Details.Tags, action.tag)
+				return next(ctx, ray)
+			case actionRoute:
+				action.route.Capture(*ray)
+				return nil
+			default:
+				return next(ctx, ray)
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package raygun
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Handler processes a single Ray as it flows through the collector. The
+// terminal Handler in the chain is the one that actually enqueues the Ray
+// for delivery.
+type Handler func(context.Context, *Ray) error
+
+// Middleware wraps a Handler to form a composable pipeline: sampling, rate
+// limiting, scrubbing, and rule-based routing are all implemented as
+// Middleware and chained together between Capture and the send worker.
+type Middleware func(next Handler) Handler
+
+// WithMiddleware appends mw, in order, to the collector's pipeline. The
+// first Middleware passed sees a Ray first; it decides whether and how the
+// rest of the chain, and eventually delivery, ever runs.
+func WithMiddleware(mw ...Middleware) RaygunCollectorConfig {
+	return func(c *RaygunCollector) {
+		c.Middlewares = append(c.Middlewares, mw...)
+	}
+}
+
+// buildHandler composes c.Middlewares around the terminal enqueue handler,
+// in the order they were registered.
+func (c *RaygunCollector) buildHandler() Handler {
+	handler := c.enqueue
+	for i := len(c.Middlewares) - 1; i >= 0; i-- {
+		handler = c.Middlewares[i](handler)
+	}
+	return handler
+}
+
+// enqueue is the terminal Handler: it records ray in the Repository (if
+// any), then pushes it onto the worker queue, respecting the collector's
+// closed, spool, WithoutSendMode, and BlockOnFull state.
+func (c *RaygunCollector) enqueue(ctx context.Context, ray *Ray) error {
+	if c.isClosed() {
+		return ErrCollectorClosed
+	}
+
+	if c.Repository != nil {
+		c.Repository.Insert(*ray)
+	}
+
+	if c.WithoutSendMode {
+		return nil
+	}
+
+	item := queuedItem{ray: *ray}
+	if c.spool != nil && !c.spool.full() {
+		if id, err := c.spool.write(*ray); err != nil {
+			c.Logger.Printf("raygun: failed to spool ray: %s", err.Error())
+		} else {
+			item.spoolID, item.spooled = id, true
+		}
+	}
+
+	c.wg.Add(1)
+
+	if c.BlockOnFull {
+		c.queue <- item
+		return nil
+	}
+
+	select {
+	case c.queue <- item:
+	default:
+		if item.spooled {
+			// Already durable on disk; the replayer will feed it into the
+			// queue once there is room.
+			c.backlog.push(item)
+			return nil
+		}
+		c.wg.Done()
+		c.OverflowHandler(*ray)
+	}
+	return nil
+}
+
+// Sample returns a Middleware that lets a Ray continue through the
+// pipeline with probability rate (0 drops everything, 1 keeps everything).
+func Sample(rate float64) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, ray *Ray) error {
+			if rate >= 1 {
+				return next(ctx, ray)
+			}
+			if rate <= 0 || rand.Float64() >= rate {
+				return nil
+			}
+			return next(ctx, ray)
+		}
+	}
+}
+
+// RateLimit returns a Middleware backed by a token bucket of the given
+// burst size refilled at perSecond tokens/sec, dropping Rays once the
+// bucket is empty. It protects Raygun from being flooded by an error storm.
+func RateLimit(perSecond int, burst int) Middleware {
+	bucket := newTokenBucket(perSecond, burst)
+	return func(next Handler) Handler {
+		return func(ctx context.Context, ray *Ray) error {
+			if !bucket.allow() {
+				return nil
+			}
+			return next(ctx, ray)
+		}
+	}
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	max    float64
+	rate   float64
+	last   time.Time
+}
+
+func newTokenBucket(perSecond, burst int) *tokenBucket {
+	return &tokenBucket{
+		tokens: float64(burst),
+		max:    float64(burst),
+		rate:   float64(perSecond),
+		last:   time.Now(),
+	}
+}
+
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
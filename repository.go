@@ -0,0 +1,105 @@
+package raygun
+
+import (
+	"fmt"
+	"sync"
+)
+
+// RayRepository stores captured Rays for local inspection, testing, or
+// replay, independent of whether they are (or will be) sent to Raygun.
+type RayRepository interface {
+	Insert(Ray)
+	Find(id string) *Ray
+	FindAll() []Ray
+	RemoveAll()
+}
+
+// WithRepository wires repo into the collector: every Ray accepted by
+// Capture* is inserted into it just before being queued for delivery, so
+// tests, debug endpoints, or an admin UI can inspect recent captures
+// without hitting Raygun.
+func WithRepository(repo RayRepository) RaygunCollectorConfig {
+	return func(c *RaygunCollector) {
+		c.Repository = repo
+	}
+}
+
+// WithoutSend puts the collector into inspection-only mode: Rays are still
+// recorded in the Repository but the HTTP worker is never started, so
+// nothing is ever sent to Raygun. It is a stronger, more inspectable
+// alternative to NoopCollector for unit tests.
+func WithoutSend() RaygunCollectorConfig {
+	return func(c *RaygunCollector) {
+		c.WithoutSendMode = true
+	}
+}
+
+// MemoryRepository is a RayRepository backed by a fixed-size in-memory
+// ring buffer: once maxItems Rays have been inserted, the oldest is
+// evicted to make room for the newest. Safe for concurrent use.
+type MemoryRepository struct {
+	mu       sync.Mutex
+	maxItems int
+	items    []Ray
+	ids      []string
+	seq      int64
+}
+
+// NewMemoryRepository returns a MemoryRepository holding at most maxItems
+// Rays. A maxItems of 0 or less means no limit.
+func NewMemoryRepository(maxItems int) *MemoryRepository {
+	return &MemoryRepository{maxItems: maxItems}
+}
+
+// Insert records ray, evicting the oldest entry first if the repository is
+// already at capacity.
+func (r *MemoryRepository) Insert(ray Ray) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.seq++
+	id := fmt.Sprintf("ray-%d", r.seq)
+
+	r.items = append(r.items, ray)
+	r.ids = append(r.ids, id)
+
+	if r.maxItems > 0 && len(r.items) > r.maxItems {
+		overflow := len(r.items) - r.maxItems
+		r.items = r.items[overflow:]
+		r.ids = r.ids[overflow:]
+	}
+}
+
+// Find returns the Ray previously inserted with the given id, or nil if no
+// such Ray is currently held.
+func (r *MemoryRepository) Find(id string) *Ray {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.ids {
+		if existing == id {
+			ray := r.items[i]
+			return &ray
+		}
+	}
+	return nil
+}
+
+// FindAll returns every Ray currently held, oldest first.
+func (r *MemoryRepository) FindAll() []Ray {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Ray, len(r.items))
+	copy(out, r.items)
+	return out
+}
+
+// RemoveAll discards every Ray currently held.
+func (r *MemoryRepository) RemoveAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.items = nil
+	r.ids = nil
+}
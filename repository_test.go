@@ -0,0 +1,82 @@
+package raygun
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMemoryRepositoryEvictsOldestOnceFull(t *testing.T) {
+	repo := NewMemoryRepository(2)
+
+	first := NewRay("first")
+	second := NewRay("second")
+	third := NewRay("third")
+
+	repo.Insert(first)
+	repo.Insert(second)
+	repo.Insert(third)
+
+	all := repo.FindAll()
+	if len(all) != 2 {
+		t.Fatalf("expected 2 Rays held at capacity, got %d", len(all))
+	}
+	if !reflect.DeepEqual(all[0], second) || !reflect.DeepEqual(all[1], third) {
+		t.Fatalf("expected the oldest Ray to be evicted, got %+v", all)
+	}
+}
+
+func TestMemoryRepositoryAssignsUniqueIDsAcrossEviction(t *testing.T) {
+	repo := NewMemoryRepository(2)
+
+	repo.Insert(NewRay("first"))
+	second := NewRay("second")
+	third := NewRay("third")
+	repo.Insert(second)
+	repo.Insert(third)
+
+	// "first" was evicted, so ray-1 must no longer resolve, and the
+	// surviving Rays must keep the distinct ids they were assigned at
+	// insert time rather than being renumbered after the eviction.
+	if found := repo.Find("ray-1"); found != nil {
+		t.Fatalf("expected the evicted Ray's id to no longer resolve, got %+v", found)
+	}
+	if found := repo.Find("ray-2"); found == nil || !reflect.DeepEqual(*found, second) {
+		t.Fatalf("expected ray-2 to still resolve to the second inserted Ray, got %+v", found)
+	}
+	if found := repo.Find("ray-3"); found == nil || !reflect.DeepEqual(*found, third) {
+		t.Fatalf("expected ray-3 to still resolve to the third inserted Ray, got %+v", found)
+	}
+}
+
+func TestMemoryRepositoryFindByID(t *testing.T) {
+	repo := NewMemoryRepository(0)
+
+	first := NewRay("first")
+	second := NewRay("second")
+	repo.Insert(first)
+	repo.Insert(second)
+
+	if found := repo.Find("ray-1"); found == nil || !reflect.DeepEqual(*found, first) {
+		t.Fatalf("expected to find the first inserted Ray by id, got %+v", found)
+	}
+	if found := repo.Find("ray-2"); found == nil || !reflect.DeepEqual(*found, second) {
+		t.Fatalf("expected to find the second inserted Ray by id, got %+v", found)
+	}
+	if found := repo.Find("ray-3"); found != nil {
+		t.Fatalf("expected no Ray for an id that was never assigned, got %+v", found)
+	}
+}
+
+func TestMemoryRepositoryRemoveAll(t *testing.T) {
+	repo := NewMemoryRepository(0)
+
+	repo.Insert(NewRay("first"))
+	repo.RemoveAll()
+
+	if all := repo.FindAll(); len(all) != 0 {
+		t.Fatalf("expected no Rays after RemoveAll, got %d", len(all))
+	}
+	if found := repo.Find("ray-1"); found != nil {
+		t.Fatalf("expected RemoveAll to drop previously assigned ids, got %+v", found)
+	}
+}
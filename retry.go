@@ -0,0 +1,122 @@
+package raygun
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy decides, given the attempt number (starting at 1) and the
+// outcome of that attempt, whether the Ray should be retried and after
+// how long. resp is nil if the request never got a response (network
+// error); err is nil if a response was received.
+type RetryPolicy func(attempt int, resp *http.Response, err error) (delay time.Duration, retry bool)
+
+const (
+	retryBaseDelay = 500 * time.Millisecond
+	retryFactor    = 2
+	retryCap       = 30 * time.Second
+	retryJitter    = 0.2
+)
+
+// DefaultRetryPolicy retries network errors and HTTP 408/429/500/502/503/504
+// responses with exponential backoff (base ~500ms, factor 2, cap ~30s) and
+// +/-20% jitter.
+func DefaultRetryPolicy(attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if err == nil && resp != nil && !isTransientStatus(resp.StatusCode) {
+		return 0, false
+	}
+
+	delay := retryBaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= retryFactor
+		if delay >= retryCap {
+			delay = retryCap
+			break
+		}
+	}
+
+	delay = time.Duration(float64(delay) * (1 + retryJitter*(2*rand.Float64()-1)))
+	return delay, true
+}
+
+func errorForStatus(status int) error {
+	return fmt.Errorf("raygun: server responded with status %d", status)
+}
+
+func isTransientStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfterDelay reports the delay requested by a Retry-After header on a
+// 429 or 503 response, in either the seconds or HTTP-date form, if present.
+func retryAfterDelay(resp *http.Response) (time.Duration, bool) {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(at); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// MaxAttempts caps the number of delivery attempts (including the first)
+// made for a Ray before it is handed to the DeadLetterHandler. The default
+// is 5.
+func MaxAttempts(n int) RaygunCollectorConfig {
+	return func(c *RaygunCollector) {
+		c.MaxAttempts = n
+	}
+}
+
+// WithRetryPolicy lets callers supply their own RetryPolicy in place of
+// DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) RaygunCollectorConfig {
+	return func(c *RaygunCollector) {
+		c.RetryPolicy = policy
+	}
+}
+
+// DeadLetterHandler is invoked for a Ray that is given up on without being
+// delivered: MaxAttempts was reached, the RetryPolicy declined to retry, or
+// the collector shut down while the Ray was still pending.
+type DeadLetterHandler func(Ray, error)
+
+// WithDeadLetterHandler overrides the default behaviour of logging Rays
+// that are given up on without being delivered.
+func WithDeadLetterHandler(handler DeadLetterHandler) RaygunCollectorConfig {
+	return func(c *RaygunCollector) {
+		c.DeadLetterHandler = handler
+	}
+}
+
+func defaultDeadLetterHandler(logger interface {
+	Printf(string, ...interface{})
+}) DeadLetterHandler {
+	return func(ray Ray, err error) {
+		logger.Printf("raygun: giving up on ray after exhausting retries: %s", err.Error())
+	}
+}
@@ -0,0 +1,89 @@
+package raygun
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDefaultRetryPolicyBackoffGrowsAndJitters(t *testing.T) {
+	networkErr := errors.New("network error")
+
+	base := retryBaseDelay
+	for attempt := 1; attempt <= 5; attempt++ {
+		delay, retry := DefaultRetryPolicy(attempt, nil, networkErr)
+		if !retry {
+			t.Fatalf("attempt %d: expected retry=true for a network error", attempt)
+		}
+
+		lo := time.Duration(float64(base) * (1 - retryJitter))
+		hi := time.Duration(float64(base) * (1 + retryJitter))
+		if delay < lo || delay > hi {
+			t.Fatalf("attempt %d: delay %v outside expected jitter band [%v, %v]", attempt, delay, lo, hi)
+		}
+
+		if base < retryCap {
+			base *= retryFactor
+			if base > retryCap {
+				base = retryCap
+			}
+		}
+	}
+}
+
+func TestDefaultRetryPolicyNonTransientStatus(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusBadRequest}
+	if _, retry := DefaultRetryPolicy(1, resp, nil); retry {
+		t.Fatalf("expected no retry for a non-transient 400 response")
+	}
+}
+
+func TestDefaultRetryPolicyTransientStatus(t *testing.T) {
+	for _, status := range []int{
+		http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout,
+	} {
+		resp := &http.Response{StatusCode: status}
+		if _, retry := DefaultRetryPolicy(1, resp, nil); !retry {
+			t.Fatalf("expected retry=true for transient status %d", status)
+		}
+	}
+}
+
+func TestRetryAfterDelaySeconds(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"2"}},
+	}
+	delay, ok := retryAfterDelay(resp)
+	if !ok || delay != 2*time.Second {
+		t.Fatalf("got delay=%v ok=%v, want 2s/true", delay, ok)
+	}
+}
+
+func TestRetryAfterDelayHTTPDate(t *testing.T) {
+	at := time.Now().Add(5 * time.Second)
+	resp := &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{"Retry-After": []string{at.UTC().Format(http.TimeFormat)}},
+	}
+	delay, ok := retryAfterDelay(resp)
+	if !ok {
+		t.Fatalf("expected an HTTP-date Retry-After to parse")
+	}
+	if delay <= 0 || delay > 5*time.Second {
+		t.Fatalf("delay %v outside expected range", delay)
+	}
+}
+
+func TestRetryAfterDelayIgnoredOnNonRetryableStatus(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Retry-After": []string{"2"}},
+	}
+	if _, ok := retryAfterDelay(resp); ok {
+		t.Fatalf("expected Retry-After to be ignored on a 200 response")
+	}
+}
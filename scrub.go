@@ -0,0 +1,86 @@
+package raygun
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Scrub returns a Middleware that redacts the named fields wherever they
+// appear as keys (case-insensitively) inside a Ray's UserCustomData,
+// replacing the value with "[SCRUBBED]". Maps, slices, and structs (including
+// the arbitrary interface{} that WithCustomData accepts) are walked
+// recursively so redaction reaches nested payloads, not just the top level.
+//
+// Only UserCustomData is walked: request headers and stack-frame variables
+// are not scrubbed.
+func Scrub(fields ...string) Middleware {
+	redact := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		redact[strings.ToLower(f)] = true
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx context.Context, ray *Ray) error {
+			ray.Details.UserCustomData = scrubValue(reflect.ValueOf(ray.Details.UserCustomData), redact)
+			return next(ctx, ray)
+		}
+	}
+}
+
+// scrubValue redacts matching keys out of v, recursing through maps,
+// slices/arrays, structs, and pointers so a struct passed to WithCustomData
+// is covered the same as a map[string]interface{} built by hand.
+func scrubValue(v reflect.Value, redact map[string]bool) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		return scrubValue(v.Elem(), redact)
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return scrubValue(v.Elem(), redact)
+	case reflect.Map:
+		out := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			k := fmt.Sprint(key.Interface())
+			if redact[strings.ToLower(k)] {
+				out[k] = "[SCRUBBED]"
+				continue
+			}
+			out[k] = scrubValue(v.MapIndex(key), redact)
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		out := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out[i] = scrubValue(v.Index(i), redact)
+		}
+		return out
+	case reflect.Struct:
+		out := make(map[string]interface{}, v.NumField())
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue // unexported
+			}
+			if redact[strings.ToLower(field.Name)] {
+				out[field.Name] = "[SCRUBBED]"
+				continue
+			}
+			out[field.Name] = scrubValue(v.Field(i), redact)
+		}
+		return out
+	default:
+		if !v.CanInterface() {
+			return nil
+		}
+		return v.Interface()
+	}
+}
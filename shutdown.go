@@ -0,0 +1,118 @@
+package raygun
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrCollectorClosed is returned by the collector's pipeline, and logged by
+// Capture* methods, once Close has been called; the collector no longer
+// accepts new Rays.
+var ErrCollectorClosed = errors.New("raygun: collector is closed")
+
+// OverflowHandler receives a Ray that could not be enqueued because the
+// queue was full and BlockOnFull is false.
+type OverflowHandler func(Ray)
+
+// BlockOnFull controls what happens when the internal queue is full. When
+// false (the default), Capture* hands the Ray to the OverflowHandler
+// instead of blocking the caller. When true, Capture* blocks until space is
+// available, matching the original behaviour.
+func BlockOnFull(block bool) RaygunCollectorConfig {
+	return func(c *RaygunCollector) {
+		c.BlockOnFull = block
+	}
+}
+
+// WithOverflowHandler overrides the default behaviour of logging Rays
+// dropped because the queue was full.
+func WithOverflowHandler(handler OverflowHandler) RaygunCollectorConfig {
+	return func(c *RaygunCollector) {
+		c.OverflowHandler = handler
+	}
+}
+
+func defaultOverflowHandler(logger interface {
+	Printf(string, ...interface{})
+}) OverflowHandler {
+	return func(ray Ray) {
+		logger.Printf("raygun: dropping ray, queue is full")
+	}
+}
+
+// Close stops the collector from accepting new Rays (subsequent Capture*
+// calls return/log ErrCollectorClosed), drains the queue until it is empty
+// or ctx is done, then closes idle HTTP connections. If ctx expires before
+// the queue and spool backlog drain naturally, whatever is left is
+// force-dead-lettered so it isn't silently dropped and so c.wg still
+// reaches zero - otherwise every later Wait/Flush on this collector would
+// block forever waiting on Rays no worker will ever pick up again.
+//
+// Close waits for the spool replayer goroutine (if any) to fully stop
+// before its final queue drain, so that goroutine can never push a Ray
+// into c.queue after the drain has already run - see startReplayer.
+func (c *RaygunCollector) Close(ctx context.Context) error {
+	atomic.StoreInt32(&c.closed, 1)
+
+	err := c.Flush(ctx)
+	if err != nil {
+		atomic.StoreInt32(&c.draining, 1)
+		if c.backlog != nil {
+			for _, item := range c.backlog.drainAll() {
+				c.deadLetterAbandoned(item, err)
+			}
+		}
+	}
+
+	close(c.done)
+
+	if c.backlog != nil {
+		c.backlog.close()
+		<-c.replayerStopped
+	}
+
+	if err != nil {
+		c.drainQueue(err)
+	}
+	c.client.CloseIdleConnections()
+	return err
+}
+
+// drainQueue dead-letters every Ray still sitting in c.queue. Called by
+// Close after c.done is closed and the replayer has fully stopped (so
+// nothing will ever read from, or write into, c.queue again) when Flush's
+// deadline expired, to resolve whatever was left rather than leaving it
+// stuck with c.wg never reaching zero.
+func (c *RaygunCollector) drainQueue(err error) {
+	for {
+		select {
+		case item := <-c.queue:
+			c.deadLetterAbandoned(item, err)
+		default:
+			return
+		}
+	}
+}
+
+// Flush blocks until the current queue depth reaches zero - i.e. every
+// Ray captured so far has been delivered or dead-lettered - or until ctx
+// is done, whichever comes first.
+func (c *RaygunCollector) Flush(ctx context.Context) error {
+	drained := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *RaygunCollector) isClosed() bool {
+	return atomic.LoadInt32(&c.closed) == 1
+}
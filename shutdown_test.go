@@ -0,0 +1,52 @@
+package raygun
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCloseShortDeadlineResolvesRemainingQueueItems(t *testing.T) {
+	var mu sync.Mutex
+	var deadLettered int
+
+	collector := NewCollector("app", "key", Workers(0), QueueSize(4),
+		WithDeadLetterHandler(func(Ray, error) {
+			mu.Lock()
+			deadLettered++
+			mu.Unlock()
+		}),
+	).(*RaygunCollector)
+
+	// Workers(0) starts no worker goroutines, so these sit in c.queue for
+	// the whole test - reproducing Close racing a Flush deadline against a
+	// queue nothing will ever drain.
+	collector.CaptureMessage("first")
+	collector.CaptureMessage("second")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := collector.Close(ctx); err == nil {
+		t.Fatalf("expected Close to report the context deadline, got nil")
+	}
+
+	mu.Lock()
+	count := deadLettered
+	mu.Unlock()
+	if count != 2 {
+		t.Fatalf("expected both queued Rays to be dead-lettered, got %d", count)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		collector.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Wait() blocked forever after Close gave up on remaining queue items")
+	}
+}
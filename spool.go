@@ -0,0 +1,409 @@
+package raygun
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// spoolSegmentMaxBytes is the size at which the active segment file is
+// rotated to a new one.
+const spoolSegmentMaxBytes = 64 * 1024 * 1024
+
+// spoolRecordID identifies a single record within the spool: the segment
+// it was written to and its byte offset within that segment's file.
+type spoolRecordID struct {
+	segment int
+	offset  int64
+}
+
+// spool is an on-disk, append-only, length-prefixed-JSON record log used to
+// survive process crashes and queue-overflow without losing Rays. Each
+// accepted Ray is written to the active segment before being queued; once a
+// Ray is finally delivered or dead-lettered it is acknowledged, and
+// segments whose records are all acknowledged are compacted away.
+type spool struct {
+	dir      string
+	maxBytes int64
+
+	mu       sync.Mutex
+	file     *os.File
+	segment  int
+	size     int64
+	acked    map[int]map[int64]bool
+	recorded map[int]int64 // segment -> total bytes written, for compaction sizing
+}
+
+func segmentPath(dir string, segment int) string {
+	return filepath.Join(dir, fmt.Sprintf("raygun-%06d.log", segment))
+}
+
+func ackPath(dir string, segment int) string {
+	return filepath.Join(dir, fmt.Sprintf("raygun-%06d.ack", segment))
+}
+
+// pendingRecord is an unacknowledged record found on disk at startup,
+// paired with the spoolRecordID needed to ack it once it is finally
+// delivered or dead-lettered.
+type pendingRecord struct {
+	id  spoolRecordID
+	ray Ray
+}
+
+// openSpool creates dir if necessary, opens (or starts) the newest segment
+// for appending, and returns the spool along with every unacknowledged
+// record found on disk so the caller can re-enqueue them.
+func openSpool(dir string, maxBytes int64) (*spool, []pendingRecord, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, err
+	}
+
+	segments, err := existingSegments(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s := &spool{
+		dir:      dir,
+		maxBytes: maxBytes,
+		acked:    make(map[int]map[int64]bool),
+		recorded: make(map[int]int64),
+	}
+
+	var pending []pendingRecord
+	for _, seg := range segments {
+		acked, err := loadAcked(ackPath(dir, seg))
+		if err != nil {
+			return nil, nil, err
+		}
+		s.acked[seg] = acked
+
+		_, size, err := readSegment(segmentPath(dir, seg), acked, func(offset int64, ray Ray) {
+			pending = append(pending, pendingRecord{id: spoolRecordID{segment: seg, offset: offset}, ray: ray})
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		s.recorded[seg] = size
+		s.segment = seg
+	}
+
+	if len(segments) == 0 {
+		s.segment = 1
+	}
+
+	f, err := os.OpenFile(segmentPath(dir, s.segment), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	s.file = f
+	s.size = info.Size()
+	if _, ok := s.acked[s.segment]; !ok {
+		s.acked[s.segment] = make(map[int64]bool)
+	}
+
+	s.compactLocked()
+
+	return s, pending, nil
+}
+
+func existingSegments(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []int
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "raygun-") || !strings.HasSuffix(name, ".log") {
+			continue
+		}
+		num := strings.TrimSuffix(strings.TrimPrefix(name, "raygun-"), ".log")
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, n)
+	}
+
+	sort.Ints(segments)
+	return segments, nil
+}
+
+func loadAcked(path string) (map[int64]bool, error) {
+	acked := make(map[int64]bool)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return acked, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		offset, err := strconv.ParseInt(strings.TrimSpace(scanner.Text()), 10, 64)
+		if err != nil {
+			continue
+		}
+		acked[offset] = true
+	}
+
+	return acked, scanner.Err()
+}
+
+// readSegment walks every length-prefixed record in the segment file at
+// path, invoking onPending (with the record's offset) for each one not
+// present in acked, and returns the total record count and byte size of
+// the file.
+func readSegment(path string, acked map[int64]bool, onPending func(int64, Ray)) (int, int64, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	var (
+		count  int
+		offset int64
+	)
+
+	lenBuf := make([]byte, 4)
+	for {
+		pos := offset
+		if _, err := io.ReadFull(f, lenBuf); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return count, offset, nil
+		}
+		offset += 4
+
+		length := binary.BigEndian.Uint32(lenBuf)
+		body := make([]byte, length)
+		if _, err := io.ReadFull(f, body); err != nil {
+			break
+		}
+		offset += int64(length)
+		count++
+
+		if acked[pos] {
+			continue
+		}
+
+		var ray Ray
+		if err := json.Unmarshal(body, &ray); err != nil {
+			continue
+		}
+		onPending(pos, ray)
+	}
+
+	return count, offset, nil
+}
+
+// write appends ray to the active segment, rotating to a new segment if
+// this record would push it past spoolSegmentMaxBytes.
+func (s *spool) write(ray Ray) (spoolRecordID, error) {
+	body, err := json.Marshal(ray)
+	if err != nil {
+		return spoolRecordID{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size > 0 && s.size+int64(len(body))+4 > spoolSegmentMaxBytes {
+		if err := s.rotateLocked(); err != nil {
+			return spoolRecordID{}, err
+		}
+	}
+
+	offset := s.size
+
+	lenBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(lenBuf, uint32(len(body)))
+	if _, err := s.file.Write(lenBuf); err != nil {
+		return spoolRecordID{}, err
+	}
+	if _, err := s.file.Write(body); err != nil {
+		return spoolRecordID{}, err
+	}
+
+	s.size += int64(len(body)) + 4
+	s.recorded[s.segment] = s.size
+
+	return spoolRecordID{segment: s.segment, offset: offset}, nil
+}
+
+func (s *spool) rotateLocked() error {
+	s.file.Close()
+	s.segment++
+	s.size = 0
+	s.acked[s.segment] = make(map[int64]bool)
+
+	f, err := os.OpenFile(segmentPath(s.dir, s.segment), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.file = f
+	return nil
+}
+
+// diskUsage reports the approximate total size, in bytes, of every segment
+// file still present in the spool directory.
+func (s *spool) diskUsage() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var total int64
+	for _, size := range s.recorded {
+		total += size
+	}
+	return total
+}
+
+// full reports whether the spool has reached its configured size budget.
+func (s *spool) full() bool {
+	if s.maxBytes <= 0 {
+		return false
+	}
+	return s.diskUsage() >= s.maxBytes
+}
+
+// ack marks id as durably delivered (or dead-lettered) and appends it to
+// the owning segment's checkpoint file, then opportunistically compacts
+// fully-acknowledged segments.
+func (s *spool) ack(id spoolRecordID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.acked[id.segment]; !ok {
+		s.acked[id.segment] = make(map[int64]bool)
+	}
+	s.acked[id.segment][id.offset] = true
+
+	f, err := os.OpenFile(ackPath(s.dir, id.segment), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "%d\n", id.offset); err != nil {
+		return err
+	}
+
+	s.compactLocked()
+	return nil
+}
+
+// compactLocked deletes every non-active segment whose every record has
+// been acknowledged. Callers must hold s.mu.
+func (s *spool) compactLocked() {
+	for segment, acked := range s.acked {
+		if segment == s.segment {
+			continue
+		}
+
+		total, _, err := readSegment(segmentPath(s.dir, segment), nil, func(int64, Ray) {})
+		if err != nil {
+			continue
+		}
+		if total == 0 || len(acked) < total {
+			continue
+		}
+
+		os.Remove(segmentPath(s.dir, segment))
+		os.Remove(ackPath(s.dir, segment))
+		delete(s.acked, segment)
+		delete(s.recorded, segment)
+	}
+}
+
+// spoolBacklog is an unbounded FIFO of queuedItems waiting for room in the
+// in-memory queue. It is fed by items that were spooled but couldn't be
+// queued immediately (queue full) and by records replayed from disk at
+// startup, and drained by the replayer goroutine.
+type spoolBacklog struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  []queuedItem
+	closed bool
+}
+
+func newSpoolBacklog() *spoolBacklog {
+	b := &spoolBacklog{}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *spoolBacklog) push(item queuedItem) {
+	b.mu.Lock()
+	b.items = append(b.items, item)
+	b.mu.Unlock()
+	b.cond.Signal()
+}
+
+func (b *spoolBacklog) pop() (queuedItem, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for len(b.items) == 0 && !b.closed {
+		b.cond.Wait()
+	}
+	if len(b.items) == 0 {
+		return queuedItem{}, false
+	}
+
+	item := b.items[0]
+	b.items = b.items[1:]
+	return item, true
+}
+
+// drainAll empties the backlog and returns whatever it held, without
+// blocking for more items to ever arrive. Used by Close to force-resolve
+// whatever is left once Flush's deadline has expired.
+func (b *spoolBacklog) drainAll() []queuedItem {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	items := b.items
+	b.items = nil
+	return items
+}
+
+func (b *spoolBacklog) close() {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+// WithSpool enables an on-disk spool rooted at dir, capped at approximately
+// maxBytes of segment data (0 means unbounded). Every Ray accepted by
+// Capture* is durably recorded before it is queued; unacknowledged records
+// left over from a previous run are replayed on the next NewCollector call.
+func WithSpool(dir string, maxBytes int64) RaygunCollectorConfig {
+	return func(c *RaygunCollector) {
+		c.SpoolDir = dir
+		c.SpoolMaxBytes = maxBytes
+	}
+}
@@ -0,0 +1,89 @@
+package raygun
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSpoolReplayAfterRestart(t *testing.T) {
+	dir, err := os.MkdirTemp("", "raygun-spool-*")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sp, pending, err := openSpool(dir, 0)
+	if err != nil {
+		t.Fatalf("openSpool: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("expected no pending records in a fresh spool, got %d", len(pending))
+	}
+
+	acked := NewRay("acked before restart")
+	unacked := NewRay("still pending after restart")
+
+	ackedID, err := sp.write(acked)
+	if err != nil {
+		t.Fatalf("write acked: %v", err)
+	}
+	if _, err := sp.write(unacked); err != nil {
+		t.Fatalf("write unacked: %v", err)
+	}
+	if err := sp.ack(ackedID); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+
+	// Simulate a crash-and-restart by reopening the same spool directory.
+	_, pending, err = openSpool(dir, 0)
+	if err != nil {
+		t.Fatalf("reopen spool: %v", err)
+	}
+
+	if len(pending) != 1 {
+		t.Fatalf("expected exactly 1 unacknowledged record after restart, got %d", len(pending))
+	}
+	if !reflect.DeepEqual(pending[0].ray, unacked) {
+		t.Fatalf("replayed record does not match the unacked ray: got %+v, want %+v", pending[0].ray, unacked)
+	}
+}
+
+func TestSpoolCompactsFullyAckedSegments(t *testing.T) {
+	dir, err := os.MkdirTemp("", "raygun-spool-*")
+	if err != nil {
+		t.Fatalf("MkdirTemp: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sp, _, err := openSpool(dir, 0)
+	if err != nil {
+		t.Fatalf("openSpool: %v", err)
+	}
+
+	id, err := sp.write(NewRay("only record in this segment"))
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	segment := id.segment
+
+	if err := sp.ack(id); err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+
+	// Rotate to a new active segment so the acked one is eligible for
+	// compaction, then give compactLocked another chance to run.
+	sp.mu.Lock()
+	err = sp.rotateLocked()
+	sp.mu.Unlock()
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	if err := sp.ack(id); err != nil {
+		t.Fatalf("re-ack: %v", err)
+	}
+
+	if _, err := os.Stat(segmentPath(dir, segment)); !os.IsNotExist(err) {
+		t.Fatalf("expected fully-acknowledged segment %d to be compacted away, stat err = %v", segment, err)
+	}
+}